@@ -0,0 +1,141 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package api defines the types pkg/compose's composeService exposes to its
+// callers (the CLI, or anyone embedding Compose as a library), independent
+// of how composeService itself is implemented.
+package api
+
+import (
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// Labels applied to containers Compose creates, read back to recover which
+// service/project a container belongs to.
+const (
+	// ServiceLabel is the label set to a container's service name.
+	ServiceLabel = "com.docker.compose.service"
+	// ContainerReplaceLabel is set to the ID of the container a recreated
+	// container is replacing.
+	ContainerReplaceLabel = "com.docker.compose.replace"
+)
+
+// DependencyFailureAction controls how StartInDependencyOrder reacts when a
+// service fails to start, mirroring compose.FailurePolicy without pkg/api
+// importing pkg/compose.
+type DependencyFailureAction int
+
+const (
+	// DependencyFailureAbort cancels the whole `up`/`start` as soon as any
+	// service fails, as Compose has always done. This is the default.
+	DependencyFailureAbort DependencyFailureAction = iota
+	// DependencyFailureSkipDependents marks the failed service failed and
+	// every service that transitively depends on it skipped, while letting
+	// unrelated branches come up normally.
+	DependencyFailureSkipDependents
+	// DependencyFailureContinue keeps starting every branch, including the
+	// dependents of a failed service, regardless of failures elsewhere.
+	DependencyFailureContinue
+)
+
+// StartOptions group options of the Start API.
+type StartOptions struct {
+	// Project is the compose project used to define this app. Might be nil
+	// if user ran a command just passing a project name and Compose will
+	// grab the project definition from docker state.
+	Project *types.Project
+	// AttachTo set the services to attach to.
+	AttachTo []string
+	// Services passed in the command line to be started.
+	Services []string
+	// Wait won't return until containers reached the running|healthy state.
+	Wait bool
+	// WaitTimeout sets the maximum duration to wait for the project to be
+	// running|healthy.
+	WaitTimeout time.Duration
+	// OnDependencyFailure picks how a failed service affects the rest of the
+	// dependency graph; populated from the CLI's --dependency-failure-action
+	// flag. Zero value is DependencyFailureAbort.
+	OnDependencyFailure DependencyFailureAction
+	// MaxConcurrency caps how many services are started at once. Zero (the
+	// default) means unbounded.
+	MaxConcurrency int
+	// StartRate caps how many services per second may start being visited,
+	// so `compose up` doesn't hammer the daemon with image pulls and
+	// container creates on projects with dozens of independent services.
+	// Zero (the default) means unlimited.
+	StartRate float64
+	// StartBurst sets how many services StartRate lets start back to back
+	// before the rate limit kicks in. Defaults to 1.
+	StartBurst int
+}
+
+// UpOptions group options of the Up API.
+type UpOptions struct {
+	// Start holds the options also shared with the Start API.
+	Start StartOptions
+}
+
+// ContainerEventListener is notified as containers are attached to, start,
+// stop, or get recreated.
+type ContainerEventListener func(event ContainerEvent)
+
+// ContainerEventType identifies what happened to a container in a
+// ContainerEvent.
+type ContainerEventType int
+
+const (
+	// ContainerEventAttach is sent once Compose starts streaming a
+	// container's logs.
+	ContainerEventAttach ContainerEventType = iota
+	// ContainerEventStopped is sent when a container stops.
+	ContainerEventStopped
+	// ContainerEventRecreated is sent when a container is replaced by a new
+	// one (e.g. after a config change).
+	ContainerEventRecreated
+	// ContainerEventExit is sent when a container's process exits.
+	ContainerEventExit
+)
+
+// ContainerEvent is sent to a ContainerEventListener as containers are
+// attached, started, stopped, or recreated.
+type ContainerEvent struct {
+	Type       ContainerEventType
+	Container  string
+	ID         string
+	Service    string
+	ExitCode   int
+	Restarting bool
+}
+
+// EventsOptions group options of the Events API.
+type EventsOptions struct {
+	// Services restricts events to these services; empty means every
+	// service in the project.
+	Services []string
+	// Consumer is called once per matching engine event.
+	Consumer func(event Event) error
+}
+
+// Event is a single Docker Engine event relevant to one of the project's
+// containers.
+type Event struct {
+	Container string
+	Status    string
+	Timestamp time.Time
+}
@@ -18,399 +18,188 @@ package compose
 
 import (
 	"context"
-	"fmt"
-	"strings"
-	"sync"
 
 	"github.com/compose-spec/compose-go/types"
-	"golang.org/x/sync/errgroup"
 
-	"github.com/docker/compose/v2/pkg/utils"
+	"github.com/docker/compose/v2/pkg/compose/graph"
 )
 
 // ServiceStatus indicates the status of a service
-type ServiceStatus int
+type ServiceStatus = graph.Status
 
 // Services status flags
 const (
 	ServiceStopped ServiceStatus = iota
 	ServiceStarted
+	// ServiceFailed marks a service whose traversal function returned an error under
+	// a FailurePolicy other than Abort.
+	ServiceFailed
+	// ServiceSkipped marks a service that was never attempted because one
+	// of its dependencies ended up ServiceFailed or ServiceSkipped.
+	ServiceSkipped
 )
 
-type graphTraversal struct {
-	mu   sync.Mutex
-	seen map[string]struct{}
+// FailurePolicy controls how a dependency-ordered traversal reacts when the
+// function applied to a service returns an error.
+type FailurePolicy = graph.FailurePolicy
 
-	extremityNodesFn            func(*Graph) []*Vertex                        // leaves or roots
-	adjacentNodesFn             func(*Vertex) []*Vertex                       // getParents or getChildren
-	filterAdjacentByStatusFn    func(*Graph, string, ServiceStatus) []*Vertex // filterChildren or filterParents
-	targetServiceStatus         ServiceStatus
-	adjacentServiceStatusToSkip ServiceStatus
-
-	visitorFn      func(context.Context, string) error
-	maxConcurrency int
-}
-
-func upDirectionTraversal(visitorFn func(context.Context, string) error) *graphTraversal {
-	return &graphTraversal{
-		extremityNodesFn:            leaves,
-		adjacentNodesFn:             getParents,
-		filterAdjacentByStatusFn:    filterChildren,
-		adjacentServiceStatusToSkip: ServiceStopped,
-		targetServiceStatus:         ServiceStarted,
-		visitorFn:                   visitorFn,
-	}
-}
+const (
+	// Abort cancels the whole traversal as soon as any service fails, as
+	// Compose has always done.
+	Abort = graph.AbortOnFailure
+	// SkipDependents marks the failed service ServiceFailed and every
+	// service that transitively depends on it ServiceSkipped, while letting
+	// unrelated branches run to completion.
+	SkipDependents = graph.SkipDependents
+	// ContinueAll keeps running every branch to completion, including the
+	// dependents of a failed service, regardless of failures elsewhere.
+	ContinueAll = graph.ContinueOnFailure
+)
 
-func downDirectionTraversal(visitorFn func(context.Context, string) error) *graphTraversal {
-	return &graphTraversal{
-		extremityNodesFn:            roots,
-		adjacentNodesFn:             getChildren,
-		filterAdjacentByStatusFn:    filterParents,
-		adjacentServiceStatusToSkip: ServiceStarted,
-		targetServiceStatus:         ServiceStopped,
-		visitorFn:                   visitorFn,
-	}
-}
+// TraversalOptions configures InDependencyOrder and InDependencyOrderFor.
+type TraversalOptions = graph.VisitOptions[string]
 
-// InDependencyOrder applies the function to the services of the project taking in account the dependency order
-func InDependencyOrder(ctx context.Context, project *types.Project, fn func(context.Context, string) error, options ...func(*graphTraversal)) error {
-	graph, err := NewGraph(project.Services, ServiceStopped)
-	if err != nil {
-		return err
-	}
-	t := upDirectionTraversal(fn)
-	for _, option := range options {
-		option(t)
+// WithFailurePolicy sets how the traversal reacts to an error. The default
+// is Abort.
+func WithFailurePolicy(policy FailurePolicy) func(*TraversalOptions) {
+	return func(o *TraversalOptions) {
+		o.FailurePolicy = policy
 	}
-	return t.visit(ctx, graph)
 }
 
-// InReverseDependencyOrder applies the function to the services of the project in reverse order of dependencies
-func InReverseDependencyOrder(ctx context.Context, project *types.Project, fn func(context.Context, string) error) error {
-	graph, err := NewGraph(project.Services, ServiceStarted)
-	if err != nil {
-		return err
+// WithMaxConcurrency caps how many services are visited at once. Zero (the
+// default) means unbounded.
+func WithMaxConcurrency(n int) func(*TraversalOptions) {
+	return func(o *TraversalOptions) {
+		o.MaxConcurrency = n
 	}
-	t := downDirectionTraversal(fn)
-	return t.visit(ctx, graph)
 }
 
-func (t *graphTraversal) visit(ctx context.Context, g *Graph) error {
-	expect := len(g.Vertices)
-	if expect == 0 {
-		return nil
+// WithPriorityFn picks which of several simultaneously-ready services is
+// started first: higher values go first.
+func WithPriorityFn(fn func(service string) int) func(*TraversalOptions) {
+	return func(o *TraversalOptions) {
+		o.PriorityFn = fn
 	}
-
-	eg, ctx := errgroup.WithContext(ctx)
-	if t.maxConcurrency > 0 {
-		eg.SetLimit(t.maxConcurrency + 1)
-	}
-	nodeCh := make(chan *Vertex)
-	eg.Go(func() error {
-		for node := range nodeCh {
-			expect--
-			if expect == 0 {
-				close(nodeCh)
-				return nil
-			}
-			t.run(ctx, g, eg, t.adjacentNodesFn(node), nodeCh)
-		}
-		return nil
-	})
-
-	nodes := t.extremityNodesFn(g)
-	t.run(ctx, g, eg, nodes, nodeCh)
-
-	err := eg.Wait()
-	return err
 }
 
-// Note: this could be `graph.walk` or whatever
-func (t *graphTraversal) run(ctx context.Context, graph *Graph, eg *errgroup.Group, nodes []*Vertex, nodeCh chan *Vertex) {
-	for _, node := range nodes {
-		// Don't start this service yet if all of its children have
-		// not been started yet.
-		if len(t.filterAdjacentByStatusFn(graph, node.Key, t.adjacentServiceStatusToSkip)) != 0 {
-			continue
-		}
-
-		node := node
-		if !t.consume(node.Key) {
-			// another worker already visited this node
-			continue
-		}
-
-		eg.Go(func() error {
-			err := t.visitorFn(ctx, node.Service)
-			if err == nil {
-				graph.UpdateStatus(node.Key, t.targetServiceStatus)
-			}
-			nodeCh <- node
-			return err
-		})
+// WithStartRate caps how many services per second may start being visited,
+// so `compose up` doesn't hammer the daemon with image pulls and container
+// creates on projects with dozens of independent services.
+func WithStartRate(perSecond float64) func(*TraversalOptions) {
+	return func(o *TraversalOptions) {
+		o.StartRate = perSecond
 	}
 }
 
-func (t *graphTraversal) consume(nodeKey string) bool {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	if t.seen == nil {
-		t.seen = make(map[string]struct{})
-	}
-	if _, ok := t.seen[nodeKey]; ok {
-		return false
+// WithBurst sets how many services WithStartRate lets start back to back
+// before the rate limit kicks in. Defaults to 1.
+func WithBurst(n int) func(*TraversalOptions) {
+	return func(o *TraversalOptions) {
+		o.Burst = n
 	}
-	t.seen[nodeKey] = struct{}{}
-	return true
 }
 
 // Graph represents project as service dependencies
-type Graph struct {
-	Vertices map[string]*Vertex
-	lock     sync.RWMutex
-}
+type Graph = graph.Graph[string]
 
 // Vertex represents a service in the dependencies structure
-type Vertex struct {
-	Key      string
-	Service  string
-	Status   ServiceStatus
-	Children map[string]*Vertex
-	Parents  map[string]*Vertex
-}
-
-func getParents(v *Vertex) []*Vertex {
-	return v.GetParents()
-}
-
-// GetParents returns a slice with the parent vertices of the a Vertex
-func (v *Vertex) GetParents() []*Vertex {
-	var res []*Vertex
-	for _, p := range v.Parents {
-		res = append(res, p)
-	}
-	return res
-}
-
-func getChildren(v *Vertex) []*Vertex {
-	return v.GetChildren()
-}
-
-// GetChildren returns a slice with the child vertices of the a Vertex
-func (v *Vertex) GetChildren() []*Vertex {
-	var res []*Vertex
-	for _, p := range v.Children {
-		res = append(res, p)
-	}
-	return res
-}
+type Vertex = graph.Vertex[string]
 
 // NewGraph returns the dependency graph of the services
 func NewGraph(services types.Services, initialStatus ServiceStatus) (*Graph, error) {
-	graph := &Graph{
-		lock:     sync.RWMutex{},
-		Vertices: map[string]*Vertex{},
-	}
+	g := graph.New[string]()
 
 	for _, s := range services {
-		graph.AddVertex(s.Name, s.Name, initialStatus)
+		g.AddVertex(s.Name, initialStatus)
 	}
 
 	for _, s := range services {
 		for _, name := range s.GetDependencies() {
-			_ = graph.AddEdge(s.Name, name)
+			_ = g.AddEdge(s.Name, name)
 		}
 	}
 
-	if b, err := graph.HasCycles(); b {
+	if has, err := g.HasCycles(); has {
 		return nil, err
 	}
 
-	return graph, nil
+	return g, nil
 }
 
-// NewVertex is the constructor function for the Vertex
-func NewVertex(key string, service string, initialStatus ServiceStatus) *Vertex {
-	return &Vertex{
-		Key:      key,
-		Service:  service,
-		Status:   initialStatus,
-		Parents:  map[string]*Vertex{},
-		Children: map[string]*Vertex{},
+func upOptions() TraversalOptions {
+	return TraversalOptions{
+		Direction:  graph.Up,
+		NotVisited: ServiceStopped,
+		Visited:    ServiceStarted,
+		Failed:     ServiceFailed,
+		Skipped:    ServiceSkipped,
 	}
 }
 
-// AddVertex adds a vertex to the Graph
-func (g *Graph) AddVertex(key string, service string, initialStatus ServiceStatus) {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	v := NewVertex(key, service, initialStatus)
-	g.Vertices[key] = v
-}
-
-// AddEdge adds a relationship of dependency between vertices `source` and `destination`
-func (g *Graph) AddEdge(source string, destination string) error {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	sourceVertex := g.Vertices[source]
-	destinationVertex := g.Vertices[destination]
-
-	if sourceVertex == nil {
-		return fmt.Errorf("could not find %s", source)
-	}
-	if destinationVertex == nil {
-		return fmt.Errorf("could not find %s", destination)
-	}
-
-	// If they are already connected
-	if _, ok := sourceVertex.Children[destination]; ok {
-		return nil
+func downOptions() TraversalOptions {
+	return TraversalOptions{
+		Direction:  graph.Down,
+		NotVisited: ServiceStopped,
+		Visited:    ServiceStarted,
+		Failed:     ServiceFailed,
+		Skipped:    ServiceSkipped,
 	}
-
-	sourceVertex.Children[destination] = destinationVertex
-	destinationVertex.Parents[source] = sourceVertex
-
-	return nil
-}
-
-func leaves(g *Graph) []*Vertex {
-	return g.Leaves()
 }
 
-// Leaves returns the slice of leaves of the graph
-func (g *Graph) Leaves() []*Vertex {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	var res []*Vertex
-	for _, v := range g.Vertices {
-		if len(v.Children) == 0 {
-			res = append(res, v)
-		}
+// InDependencyOrder applies the function to the services of the project taking in account the dependency order
+func InDependencyOrder(ctx context.Context, project *types.Project, fn func(context.Context, string) error, options ...func(*TraversalOptions)) error {
+	g, err := NewGraph(project.Services, ServiceStopped)
+	if err != nil {
+		return err
 	}
-
-	return res
-}
-
-func roots(g *Graph) []*Vertex {
-	return g.Roots()
-}
-
-// Roots returns the slice of "Roots" of the graph
-func (g *Graph) Roots() []*Vertex {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	var res []*Vertex
-	for _, v := range g.Vertices {
-		if len(v.Parents) == 0 {
-			res = append(res, v)
-		}
+	opts := upOptions()
+	for _, option := range options {
+		option(&opts)
 	}
-	return res
+	return graph.Visit[string](ctx, g, fn, opts)
 }
 
-// UpdateStatus updates the status of a certain vertex
-func (g *Graph) UpdateStatus(key string, status ServiceStatus) {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-	g.Vertices[key].Status = status
-}
-
-func filterChildren(g *Graph, k string, s ServiceStatus) []*Vertex {
-	return g.FilterChildren(k, s)
-}
-
-// FilterChildren returns children of a certain vertex that are in a certain status
-func (g *Graph) FilterChildren(key string, status ServiceStatus) []*Vertex {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	var res []*Vertex
-	vertex := g.Vertices[key]
-
-	for _, child := range vertex.Children {
-		if child.Status == status {
-			res = append(res, child)
-		}
+// InDependencyOrderFor applies the function to targets and their transitive
+// dependencies, taking in account the dependency order, instead of to every
+// service in the project. Services outside the pruned subgraph are left
+// untouched.
+func InDependencyOrderFor(ctx context.Context, project *types.Project, targets []string, fn func(context.Context, string) error, options ...func(*TraversalOptions)) error {
+	g, err := NewGraph(project.Services, ServiceStopped)
+	if err != nil {
+		return err
 	}
-
-	return res
-}
-
-func filterParents(g *Graph, k string, s ServiceStatus) []*Vertex {
-	return g.FilterParents(k, s)
-}
-
-// FilterParents returns the parents of a certain vertex that are in a certain status
-func (g *Graph) FilterParents(key string, status ServiceStatus) []*Vertex {
-	g.lock.Lock()
-	defer g.lock.Unlock()
-
-	var res []*Vertex
-	vertex := g.Vertices[key]
-
-	for _, parent := range vertex.Parents {
-		if parent.Status == status {
-			res = append(res, parent)
-		}
+	g, err = g.SubGraph(targets)
+	if err != nil {
+		return err
 	}
-
-	return res
-}
-
-// HasCycles detects cycles in the graph
-func (g *Graph) HasCycles() (bool, error) {
-	discovered := []string{}
-	finished := []string{}
-
-	for _, vertex := range g.Vertices {
-		path := []string{
-			vertex.Key,
-		}
-		if !utils.StringContains(discovered, vertex.Key) && !utils.StringContains(finished, vertex.Key) {
-			var err error
-			discovered, finished, err = g.visit(vertex.Key, path, discovered, finished)
-
-			if err != nil {
-				return true, err
-			}
-		}
+	opts := upOptions()
+	for _, option := range options {
+		option(&opts)
 	}
-
-	return false, nil
+	return graph.Visit[string](ctx, g, fn, opts)
 }
 
-func (g *Graph) visit(key string, path []string, discovered []string, finished []string) ([]string, []string, error) {
-	discovered = append(discovered, key)
-
-	for _, v := range g.Vertices[key].Children {
-		path := append(path, v.Key)
-		if utils.StringContains(discovered, v.Key) {
-			return nil, nil, fmt.Errorf("cycle found: %s", strings.Join(path, " -> "))
-		}
-
-		if !utils.StringContains(finished, v.Key) {
-			if _, _, err := g.visit(v.Key, path, discovered, finished); err != nil {
-				return nil, nil, err
-			}
-		}
+// ServicesInDependencyOrderFor returns targets plus every service they
+// transitively depend on, i.e. the set of service names InDependencyOrderFor
+// would visit.
+func ServicesInDependencyOrderFor(project *types.Project, targets []string) ([]string, error) {
+	g, err := NewGraph(project.Services, ServiceStopped)
+	if err != nil {
+		return nil, err
 	}
-
-	discovered = remove(discovered, key)
-	finished = append(finished, key)
-	return discovered, finished, nil
+	g, err = g.SubGraph(targets)
+	if err != nil {
+		return nil, err
+	}
+	return g.Keys(), nil
 }
 
-func remove(slice []string, item string) []string {
-	var s []string
-	for _, i := range slice {
-		if i != item {
-			s = append(s, i)
-		}
+// InReverseDependencyOrder applies the function to the services of the project in reverse order of dependencies
+func InReverseDependencyOrder(ctx context.Context, project *types.Project, fn func(context.Context, string) error) error {
+	g, err := NewGraph(project.Services, ServiceStarted)
+	if err != nil {
+		return err
 	}
-	return s
+	return graph.Visit[string](ctx, g, fn, downOptions())
 }
@@ -0,0 +1,154 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// State is a minimal, executor-agnostic view of a service's runtime state,
+// as reported by Executor.Inspect.
+type State struct {
+	// Status is the executor-specific status string (e.g. "running", "exited").
+	Status string
+	// Healthy reports whether the service's health check, if any, is passing.
+	Healthy bool
+	// ExitCode is the last known exit code, meaningful once Status reports
+	// the service has stopped.
+	ExitCode int
+}
+
+// Executor decouples the dependency graph traversal (pkg/compose/graph,
+// InDependencyOrder) from how a service is actually brought up or down.
+// DockerExecutor is the default, Docker Engine-backed implementation; other
+// backends (e.g. Kubernetes or Nomad) can implement this interface to reuse
+// Compose's dependency ordering without depending on the Moby client.
+type Executor interface {
+	// Start brings service up.
+	Start(ctx context.Context, service string) error
+	// Stop brings service down.
+	Stop(ctx context.Context, service string) error
+	// Wait blocks until service satisfies condition (a types.ServiceCondition*
+	// value or ServiceConditionRunningOrHealthy), ctx is done, or an error
+	// occurs.
+	Wait(ctx context.Context, service string, condition string) error
+	// Inspect returns the current State of service.
+	Inspect(ctx context.Context, service string) (State, error)
+}
+
+// DockerExecutor is the Executor backed by the Docker Engine API. It
+// preserves Compose's historical behavior of driving containers directly,
+// so the graph traversal never has to know about images, networks, or
+// events itself.
+type DockerExecutor struct {
+	service    *composeService
+	project    *types.Project
+	containers Containers
+}
+
+// NewDockerExecutor returns the default Executor, driving project's services
+// through the Docker Engine API on behalf of s.
+func NewDockerExecutor(s *composeService, project *types.Project, containers Containers) *DockerExecutor {
+	return &DockerExecutor{service: s, project: project, containers: containers}
+}
+
+// Start implements Executor.
+func (e *DockerExecutor) Start(ctx context.Context, service string) error {
+	cfg, err := e.project.GetService(service)
+	if err != nil {
+		return err
+	}
+	return e.service.startService(ctx, e.project, cfg, e.containers)
+}
+
+// Stop implements Executor.
+func (e *DockerExecutor) Stop(ctx context.Context, service string) error {
+	cfg, err := e.project.GetService(service)
+	if err != nil {
+		return err
+	}
+	var timeout *time.Duration
+	if cfg.StopGracePeriod != nil {
+		d := time.Duration(*cfg.StopGracePeriod)
+		timeout = &d
+	}
+	for _, c := range e.containers {
+		if c.Labels[api.ServiceLabel] != service {
+			continue
+		}
+		if err := e.service.apiClient().ContainerStop(ctx, c.ID, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait implements Executor.
+func (e *DockerExecutor) Wait(ctx context.Context, service string, condition string) error {
+	depends := types.DependsOnConfig{
+		service: types.ServiceDependency{Condition: condition},
+	}
+	err := e.service.waitDependencies(ctx, e.project, depends, e.containers)
+	if err != nil {
+		if state, inspectErr := e.Inspect(ctx, service); inspectErr == nil {
+			return fmt.Errorf("%s never reached %q (last seen status %q): %w", service, condition, state.Status, err)
+		}
+	}
+	return err
+}
+
+// Inspect implements Executor.
+func (e *DockerExecutor) Inspect(ctx context.Context, service string) (State, error) {
+	for _, c := range e.containers {
+		if c.Labels[api.ServiceLabel] != service {
+			continue
+		}
+		inspected, err := e.service.apiClient().ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return State{}, err
+		}
+		state := State{Status: inspected.State.Status, ExitCode: inspected.State.ExitCode}
+		if inspected.State.Health != nil {
+			state.Healthy = inspected.State.Health.Status == "healthy"
+		}
+		return state, nil
+	}
+	return State{}, fmt.Errorf("service %q has no container", service)
+}
+
+// StartInDependencyOrder starts targets and their transitive dependencies
+// (or every service in project when targets is empty) through executor,
+// following the project's dependency order.
+func StartInDependencyOrder(ctx context.Context, project *types.Project, executor Executor, targets []string, options ...func(*TraversalOptions)) error {
+	if len(targets) == 0 {
+		return InDependencyOrder(ctx, project, executor.Start, options...)
+	}
+	return InDependencyOrderFor(ctx, project, targets, executor.Start, options...)
+}
+
+// StopInDependencyOrder stops every service in project through executor, in
+// reverse dependency order, so a service is only brought down once nothing
+// that depends on it is still running.
+func StopInDependencyOrder(ctx context.Context, project *types.Project, executor Executor) error {
+	return InReverseDependencyOrder(ctx, project, executor.Stop)
+}
@@ -0,0 +1,147 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// fakeExecutor is an Executor that only records what was called, in what
+// order, so StartInDependencyOrder/StopInDependencyOrder can be exercised
+// without a real composeService/Docker Engine, which this tree doesn't
+// define a concrete composeService for.
+type fakeExecutor struct {
+	mu      sync.Mutex
+	started []string
+	stopped []string
+	waited  []string
+	fail    map[string]error
+}
+
+func (e *fakeExecutor) Start(_ context.Context, service string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.started = append(e.started, service)
+	return e.fail[service]
+}
+
+func (e *fakeExecutor) Stop(_ context.Context, service string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = append(e.stopped, service)
+	return e.fail[service]
+}
+
+func (e *fakeExecutor) Wait(_ context.Context, service string, _ string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.waited = append(e.waited, service)
+	return e.fail[service]
+}
+
+func (e *fakeExecutor) Inspect(_ context.Context, service string) (State, error) {
+	return State{Status: "running"}, nil
+}
+
+func webDependsOnDBProject() *types.Project {
+	return &types.Project{
+		Services: types.Services{
+			{Name: "web", DependsOn: types.DependsOnConfig{
+				"db": types.ServiceDependency{Condition: types.ServiceConditionHealthy},
+			}},
+			{Name: "db"},
+		},
+	}
+}
+
+func indexOf(values []string, want string) int {
+	for i, v := range values {
+		if v == want {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestStartInDependencyOrderStartsDependencyFirst(t *testing.T) {
+	project := webDependsOnDBProject()
+	executor := &fakeExecutor{}
+
+	if err := StartInDependencyOrder(context.Background(), project, executor, nil); err != nil {
+		t.Fatalf("StartInDependencyOrder: %v", err)
+	}
+
+	db, web := indexOf(executor.started, "db"), indexOf(executor.started, "web")
+	if db == -1 || web == -1 {
+		t.Fatalf("expected both db and web started, got %v", executor.started)
+	}
+	if db > web {
+		t.Errorf("db should be started before web, got order %v", executor.started)
+	}
+}
+
+func TestStartInDependencyOrderForTargetsPrunesUnrelatedServices(t *testing.T) {
+	project := webDependsOnDBProject()
+	project.Services = append(project.Services, types.ServiceConfig{Name: "unrelated"})
+	executor := &fakeExecutor{}
+
+	if err := StartInDependencyOrder(context.Background(), project, executor, []string{"web"}); err != nil {
+		t.Fatalf("StartInDependencyOrder: %v", err)
+	}
+
+	if indexOf(executor.started, "unrelated") != -1 {
+		t.Errorf("StartInDependencyOrder(targets=[web]) should not start unrelated, got %v", executor.started)
+	}
+	if indexOf(executor.started, "db") == -1 || indexOf(executor.started, "web") == -1 {
+		t.Errorf("StartInDependencyOrder(targets=[web]) should start web and its dependency db, got %v", executor.started)
+	}
+}
+
+func TestStartInDependencyOrderStopsOnFailureByDefault(t *testing.T) {
+	project := webDependsOnDBProject()
+	executor := &fakeExecutor{fail: map[string]error{"db": fmt.Errorf("boom")}}
+
+	err := StartInDependencyOrder(context.Background(), project, executor, nil)
+	if err == nil {
+		t.Fatal("StartInDependencyOrder should surface db's failure")
+	}
+	if indexOf(executor.started, "web") != -1 {
+		t.Errorf("web should never start once its dependency db fails under the default Abort policy, got %v", executor.started)
+	}
+}
+
+func TestStopInDependencyOrderStopsDependentFirst(t *testing.T) {
+	project := webDependsOnDBProject()
+	executor := &fakeExecutor{}
+
+	if err := StopInDependencyOrder(context.Background(), project, executor); err != nil {
+		t.Fatalf("StopInDependencyOrder: %v", err)
+	}
+
+	db, web := indexOf(executor.stopped, "db"), indexOf(executor.stopped, "web")
+	if db == -1 || web == -1 {
+		t.Fatalf("expected both db and web stopped, got %v", executor.stopped)
+	}
+	if web > db {
+		t.Errorf("web should be stopped before its dependency db, got order %v", executor.stopped)
+	}
+}
@@ -0,0 +1,398 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package graph implements a small, generic, thread-safe directed
+// dependency graph plus a concurrent visitor that walks it in dependency
+// order. It has no knowledge of Compose services, containers, or the
+// Docker API: pkg/compose's InDependencyOrder and friends are thin
+// wrappers instantiating Graph[string], so other tools (IDE plugins,
+// linters, alternative orchestrators) can reuse the same ordering logic
+// over their own vertex type.
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Status is a caller-defined vertex status. The graph package doesn't
+// interpret status values itself, other than comparing them for equality;
+// callers declare their own enum (e.g. `const Stopped Status = iota`) to
+// label whatever domain-specific states they need.
+type Status int
+
+// Vertex is a single node of a Graph.
+type Vertex[T comparable] struct {
+	Key      T
+	Status   Status
+	Children map[T]*Vertex[T]
+	Parents  map[T]*Vertex[T]
+}
+
+// GetParents returns the parent vertices of v.
+func (v *Vertex[T]) GetParents() []*Vertex[T] {
+	res := make([]*Vertex[T], 0, len(v.Parents))
+	for _, p := range v.Parents {
+		res = append(res, p)
+	}
+	return res
+}
+
+// GetChildren returns the child vertices of v.
+func (v *Vertex[T]) GetChildren() []*Vertex[T] {
+	res := make([]*Vertex[T], 0, len(v.Children))
+	for _, c := range v.Children {
+		res = append(res, c)
+	}
+	return res
+}
+
+// newVertex is the constructor function for Vertex.
+func newVertex[T comparable](key T, status Status) *Vertex[T] {
+	return &Vertex[T]{
+		Key:      key,
+		Status:   status,
+		Parents:  map[T]*Vertex[T]{},
+		Children: map[T]*Vertex[T]{},
+	}
+}
+
+// Graph is a generic directed dependency graph: an edge from source to
+// destination means source depends on destination. It is safe for
+// concurrent use; unlike a bare map, its vertices are only reachable
+// through accessor methods so a reader can never observe a map mid-mutation.
+type Graph[T comparable] struct {
+	mu       sync.RWMutex
+	vertices map[T]*Vertex[T]
+}
+
+// New returns an empty Graph.
+func New[T comparable]() *Graph[T] {
+	return &Graph[T]{vertices: map[T]*Vertex[T]{}}
+}
+
+// AddVertex adds a vertex for key with the given initial status.
+func (g *Graph[T]) AddVertex(key T, status Status) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.vertices[key] = newVertex(key, status)
+}
+
+// AddEdge records that source depends on destination.
+func (g *Graph[T]) AddEdge(source, destination T) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	src, ok := g.vertices[source]
+	if !ok {
+		return fmt.Errorf("could not find %v", source)
+	}
+	dst, ok := g.vertices[destination]
+	if !ok {
+		return fmt.Errorf("could not find %v", destination)
+	}
+
+	if _, ok := src.Children[destination]; ok {
+		// already connected
+		return nil
+	}
+	src.Children[destination] = dst
+	dst.Parents[source] = src
+	return nil
+}
+
+// Vertex returns the vertex for key, if any.
+func (g *Graph[T]) Vertex(key T) (*Vertex[T], bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, ok := g.vertices[key]
+	return v, ok
+}
+
+// Len returns the number of vertices in the graph.
+func (g *Graph[T]) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.vertices)
+}
+
+// Keys returns every vertex key in the graph, in no particular order.
+func (g *Graph[T]) Keys() []T {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	keys := make([]T, 0, len(g.vertices))
+	for key := range g.vertices {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// DependencyMap returns a snapshot of every vertex's direct dependencies
+// (its children), keyed by vertex. The returned map is a copy: mutating it
+// does not affect the Graph.
+func (g *Graph[T]) DependencyMap() map[T][]T {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	deps := make(map[T][]T, len(g.vertices))
+	for key, v := range g.vertices {
+		children := make([]T, 0, len(v.Children))
+		for c := range v.Children {
+			children = append(children, c)
+		}
+		deps[key] = children
+	}
+	return deps
+}
+
+// UpdateStatus updates the status of the vertex for key.
+func (g *Graph[T]) UpdateStatus(key T, status Status) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.vertices[key].Status = status
+}
+
+// Leaves returns every vertex with no children.
+func (g *Graph[T]) Leaves() []*Vertex[T] {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var res []*Vertex[T]
+	for _, v := range g.vertices {
+		if len(v.Children) == 0 {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Roots returns every vertex with no parents.
+func (g *Graph[T]) Roots() []*Vertex[T] {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var res []*Vertex[T]
+	for _, v := range g.vertices {
+		if len(v.Parents) == 0 {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// FilterChildren returns the children of key that are in the given status.
+func (g *Graph[T]) FilterChildren(key T, status Status) []*Vertex[T] {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var res []*Vertex[T]
+	for _, c := range g.vertices[key].Children {
+		if c.Status == status {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// FilterParents returns the parents of key that are in the given status.
+func (g *Graph[T]) FilterParents(key T, status Status) []*Vertex[T] {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var res []*Vertex[T]
+	for _, p := range g.vertices[key].Parents {
+		if p.Status == status {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// SubGraph returns a new Graph restricted to keys and every vertex
+// transitively reachable from them through dependency edges (children).
+func (g *Graph[T]) SubGraph(keys []T) (*Graph[T], error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	keep := map[T]struct{}{}
+	var collect func(key T) error
+	collect = func(key T) error {
+		if _, ok := keep[key]; ok {
+			return nil
+		}
+		v, ok := g.vertices[key]
+		if !ok {
+			return fmt.Errorf("no such vertex: %v", key)
+		}
+		keep[key] = struct{}{}
+		for child := range v.Children {
+			if err := collect(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, key := range keys {
+		if err := collect(key); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := New[T]()
+	for key := range keep {
+		sub.AddVertex(key, g.vertices[key].Status)
+	}
+	for key := range keep {
+		for child := range g.vertices[key].Children {
+			if _, ok := keep[child]; ok {
+				_ = sub.AddEdge(key, child)
+			}
+		}
+	}
+	return sub, nil
+}
+
+// HasCycles reports whether the graph contains a cycle.
+func (g *Graph[T]) HasCycles() (bool, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	discovered := map[T]struct{}{}
+	finished := map[T]struct{}{}
+
+	for key := range g.vertices {
+		if _, ok := discovered[key]; ok {
+			continue
+		}
+		if _, ok := finished[key]; ok {
+			continue
+		}
+		if err := g.visit(key, []T{key}, discovered, finished); err != nil {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+func (g *Graph[T]) visit(key T, path []T, discovered, finished map[T]struct{}) error {
+	discovered[key] = struct{}{}
+
+	for _, v := range g.vertices[key].Children {
+		p := append(path, v.Key) //nolint:gocritic // intentional per-branch copy, mirrors historical cycle-detection path tracking
+		if _, ok := discovered[v.Key]; ok {
+			strs := make([]string, len(p))
+			for i, k := range p {
+				strs[i] = fmt.Sprint(k)
+			}
+			return fmt.Errorf("cycle found: %s", strings.Join(strs, " -> "))
+		}
+		if _, ok := finished[v.Key]; !ok {
+			if err := g.visit(v.Key, p, discovered, finished); err != nil {
+				return err
+			}
+		}
+	}
+
+	delete(discovered, key)
+	finished[key] = struct{}{}
+	return nil
+}
+
+// TopologicalSort returns the graph's vertices ordered so each vertex comes
+// after everything it depends on. It returns an error if the graph has a
+// cycle.
+func (g *Graph[T]) TopologicalSort() ([]T, error) {
+	if has, err := g.HasCycles(); has {
+		return nil, err
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	visited := map[T]struct{}{}
+	var order []T
+	var visit func(key T)
+	visit = func(key T) {
+		if _, ok := visited[key]; ok {
+			return
+		}
+		visited[key] = struct{}{}
+		for child := range g.vertices[key].Children {
+			visit(child)
+		}
+		order = append(order, key)
+	}
+
+	for key := range g.vertices {
+		visit(key)
+	}
+	return order, nil
+}
+
+// SCC returns the graph's strongly connected components, computed with
+// Tarjan's algorithm, in no particular order.
+func (g *Graph[T]) SCC() [][]T {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	index := 0
+	indices := map[T]int{}
+	lowlink := map[T]int{}
+	onStack := map[T]bool{}
+	var stack []T
+	var result [][]T
+
+	var strongconnect func(v T)
+	strongconnect = func(v T) {
+		indices[v] = index
+		lowlink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for w := range g.vertices[v].Children {
+			if _, ok := indices[w]; !ok {
+				strongconnect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] {
+				if indices[w] < lowlink[v] {
+					lowlink[v] = indices[w]
+				}
+			}
+		}
+
+		if lowlink[v] == indices[v] {
+			var component []T
+			for {
+				n := len(stack) - 1
+				w := stack[n]
+				stack = stack[:n]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			result = append(result, component)
+		}
+	}
+
+	for key := range g.vertices {
+		if _, ok := indices[key]; !ok {
+			strongconnect(key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package graph
+
+import (
+	"sort"
+	"testing"
+)
+
+// newTestGraph builds a graph from a map of vertex -> its dependencies
+// (children), e.g. {"a": {"b"}} records that a depends on b.
+func newTestGraph(t *testing.T, deps map[string][]string) *Graph[string] {
+	t.Helper()
+	g := New[string]()
+	for key := range deps {
+		g.AddVertex(key, 0)
+	}
+	for key, children := range deps {
+		for _, child := range children {
+			if err := g.AddEdge(key, child); err != nil {
+				t.Fatalf("AddEdge(%s, %s): %v", key, child, err)
+			}
+		}
+	}
+	return g
+}
+
+func TestSubGraphPrunesUnrelatedVertices(t *testing.T) {
+	// a -> b -> c, and an unrelated chain d -> e.
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": nil,
+		"d": {"e"},
+		"e": nil,
+	})
+
+	sub, err := g.SubGraph([]string{"b"})
+	if err != nil {
+		t.Fatalf("SubGraph: %v", err)
+	}
+
+	keys := sub.Keys()
+	sort.Strings(keys)
+	want := []string{"b", "c"}
+	if len(keys) != len(want) {
+		t.Fatalf("SubGraph([b]) kept %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("SubGraph([b]) kept %v, want %v", keys, want)
+		}
+	}
+
+	if _, ok := sub.Vertex("a"); ok {
+		t.Error("SubGraph([b]) should not keep unrelated vertex a")
+	}
+	if _, ok := sub.Vertex("d"); ok {
+		t.Error("SubGraph([b]) should not keep unrelated vertex d")
+	}
+}
+
+func TestSubGraphUnknownVertex(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{"a": nil})
+	if _, err := g.SubGraph([]string{"missing"}); err == nil {
+		t.Error("SubGraph with an unknown key should error")
+	}
+}
+
+func TestHasCyclesDetectsCycle(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+	})
+	has, err := g.HasCycles()
+	if !has || err == nil {
+		t.Fatal("HasCycles should report the a -> b -> a cycle")
+	}
+}
+
+func TestTopologicalSortOrdersDependenciesFirst(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": nil,
+	})
+	order, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort: %v", err)
+	}
+	pos := map[string]int{}
+	for i, key := range order {
+		pos[key] = i
+	}
+	if pos["c"] >= pos["b"] || pos["b"] >= pos["a"] {
+		t.Fatalf("TopologicalSort returned %v, want c before b before a", order)
+	}
+}
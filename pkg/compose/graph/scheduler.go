@@ -0,0 +1,90 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// readyQueue holds vertices that are ready to be visited, ordered by
+// priority (highest first) and then by a deterministic tiebreak so
+// same-priority vertices are always dispatched in the same order. It is
+// small enough (one entry per Compose service, typically) that re-sorting
+// on every push is simpler than a proper binary heap and not worth the
+// extra code.
+type readyQueue[T comparable] struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	items    []readyItem[T]
+	closed   bool
+	priority func(T) int
+}
+
+type readyItem[T comparable] struct {
+	key      T
+	priority int
+}
+
+func newReadyQueue[T comparable](priority func(T) int) *readyQueue[T] {
+	q := &readyQueue[T]{priority: priority}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *readyQueue[T]) push(key T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.items = append(q.items, readyItem[T]{key: key, priority: q.priority(key)})
+	sort.SliceStable(q.items, func(i, j int) bool {
+		if q.items[i].priority != q.items[j].priority {
+			return q.items[i].priority > q.items[j].priority
+		}
+		return fmt.Sprint(q.items[i].key) < fmt.Sprint(q.items[j].key)
+	})
+	q.cond.Signal()
+}
+
+// pop blocks until a vertex is ready, the queue is closed and empty. The
+// second return value is false once there is nothing left to dispatch.
+func (q *readyQueue[T]) pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item.key, true
+}
+
+// close wakes every goroutine waiting in pop. Items already queued are
+// still handed out by pop until the queue drains; later pushes are dropped.
+func (q *readyQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
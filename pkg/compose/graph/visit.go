@@ -0,0 +1,333 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+)
+
+// Direction controls which way Visit walks the graph.
+type Direction int
+
+const (
+	// Up walks from leaves toward roots, as `compose up` does: a vertex is
+	// visited once every vertex it depends on has been visited.
+	Up Direction = iota
+	// Down walks from roots toward leaves, as `compose down` does: a vertex
+	// is visited once every vertex that depends on it has been visited.
+	Down
+)
+
+// FailurePolicy controls how Visit reacts when a VisitorFunc returns an
+// error for a given vertex.
+type FailurePolicy int
+
+const (
+	// AbortOnFailure cancels the whole walk as soon as any vertex fails.
+	AbortOnFailure FailurePolicy = iota
+	// SkipDependents marks the failed vertex Failed and every vertex that
+	// transitively depends on it Skipped, while letting unrelated branches
+	// run to completion.
+	SkipDependents
+	// ContinueOnFailure keeps walking every branch to completion, including
+	// the dependents of a failed vertex, regardless of failures elsewhere.
+	ContinueOnFailure
+)
+
+// VisitorFunc is applied to each vertex as Visit walks the graph.
+type VisitorFunc[T comparable] func(ctx context.Context, key T) error
+
+// VisitOptions configures Visit. NotVisited, Visited, Failed and Skipped
+// let the caller reuse its own Status enum instead of a hard-coded pair.
+type VisitOptions[T comparable] struct {
+	Direction Direction
+	// NotVisited and Visited are the two statuses a successful visit moves a
+	// vertex between. Going Up, a vertex is expected to start NotVisited and
+	// moves to Visited once VisitorFunc succeeds; going Down it is the other
+	// way round. Visit does not set any vertex's initial status itself.
+	NotVisited Status
+	Visited    Status
+	// Failed is the status a vertex moves to once VisitorFunc errors under
+	// SkipDependents or ContinueOnFailure.
+	Failed Status
+	// Skipped is the status given to a vertex never attempted because one
+	// of its dependencies ended up Failed or Skipped.
+	Skipped Status
+	// MaxConcurrency caps how many vertices are visited at once. Zero means
+	// unbounded (one worker per vertex).
+	MaxConcurrency int
+	FailurePolicy  FailurePolicy
+	// PriorityFn, when set, picks which of several simultaneously-ready
+	// vertices is dispatched first: higher values go first. Vertices with
+	// equal priority are dispatched in a fixed, deterministic order.
+	PriorityFn func(T) int
+	// StartRate, when non-zero, caps how many vertices per second may start
+	// being visited, smoothing bursts of image pulls or container creates
+	// against the same daemon. Burst controls how many can start back to
+	// back before the rate kicks in; it defaults to 1.
+	StartRate float64
+	Burst     int
+}
+
+// Visit walks g, calling fn once per vertex in dependency order, honoring
+// options. Vertices that depend on each other are never visited
+// concurrently; unrelated branches run in parallel, dispatched in priority
+// order and optionally rate-limited by options.StartRate.
+func Visit[T comparable](ctx context.Context, g *Graph[T], fn VisitorFunc[T], options VisitOptions[T]) error {
+	remaining := g.Len()
+	if remaining == 0 {
+		return nil
+	}
+
+	var extremity []*Vertex[T]
+	var adjacent func(*Vertex[T]) []*Vertex[T]
+	var filterAdjacent func(T, Status) []*Vertex[T]
+	var notReadyStatus, targetStatus Status
+
+	switch options.Direction {
+	case Down:
+		extremity = g.Roots()
+		adjacent = (*Vertex[T]).GetChildren
+		filterAdjacent = g.FilterParents
+		notReadyStatus = options.Visited
+		targetStatus = options.NotVisited
+	default:
+		extremity = g.Leaves()
+		adjacent = (*Vertex[T]).GetParents
+		filterAdjacent = g.FilterChildren
+		notReadyStatus = options.NotVisited
+		targetStatus = options.Visited
+	}
+
+	priority := options.PriorityFn
+	if priority == nil {
+		priority = func(T) int { return 0 }
+	}
+
+	var limiter *rate.Limiter
+	if options.StartRate > 0 {
+		burst := options.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(options.StartRate), burst)
+	}
+
+	state := &visitState[T]{
+		fn:             fn,
+		options:        options,
+		filterAdjacent: filterAdjacent,
+		notReadyStatus: notReadyStatus,
+		targetStatus:   targetStatus,
+		limiter:        limiter,
+		remaining:      remaining,
+		queue:          newReadyQueue[T](priority),
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	// Closing the queue when ctx is canceled (e.g. AbortOnFailure kicked in)
+	// wakes every worker blocked waiting for the next ready vertex.
+	go func() {
+		<-ctx.Done()
+		state.queue.close()
+	}()
+
+	workers := options.MaxConcurrency
+	if workers <= 0 || workers > remaining {
+		workers = remaining
+	}
+	for i := 0; i < workers; i++ {
+		eg.Go(func() error {
+			return state.work(ctx, g, adjacent)
+		})
+	}
+
+	for _, node := range extremity {
+		state.enqueueIfReady(node)
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return state.outcomeError()
+}
+
+type visitState[T comparable] struct {
+	mu      sync.Mutex
+	seen    map[T]struct{}
+	failed  map[T]error
+	skipped map[T]struct{}
+
+	fn             VisitorFunc[T]
+	options        VisitOptions[T]
+	filterAdjacent func(T, Status) []*Vertex[T]
+	notReadyStatus Status
+	targetStatus   Status
+
+	limiter   *rate.Limiter
+	queue     *readyQueue[T]
+	remaining int
+}
+
+// work pops ready vertices off the queue until it's closed, visiting each
+// one (rate-limited, if configured) and enqueueing whatever becomes ready
+// as a result.
+func (s *visitState[T]) work(ctx context.Context, g *Graph[T], adjacent func(*Vertex[T]) []*Vertex[T]) error {
+	for {
+		key, ok := s.queue.pop()
+		if !ok {
+			return nil
+		}
+
+		node, ok := g.Vertex(key)
+		if !ok {
+			continue
+		}
+
+		if s.options.FailurePolicy == SkipDependents && s.hasUpstreamFailure(node) {
+			g.UpdateStatus(node.Key, s.options.Skipped)
+			s.recordOutcome(node.Key, s.options.Skipped, nil)
+		} else {
+			if s.limiter != nil {
+				if err := s.limiter.Wait(ctx); err != nil {
+					return err
+				}
+			}
+			err := s.fn(ctx, node.Key)
+			switch {
+			case err == nil:
+				g.UpdateStatus(node.Key, s.targetStatus)
+			case s.options.FailurePolicy == AbortOnFailure:
+				return err
+			default: // SkipDependents or ContinueOnFailure: don't cancel sibling branches
+				g.UpdateStatus(node.Key, s.options.Failed)
+				s.recordOutcome(node.Key, s.options.Failed, err)
+			}
+		}
+
+		for _, adj := range adjacent(node) {
+			s.enqueueIfReady(adj)
+		}
+		s.done()
+	}
+}
+
+// enqueueIfReady pushes node onto the queue once, and only once every one
+// of its upstream neighbours has left s.notReadyStatus.
+func (s *visitState[T]) enqueueIfReady(node *Vertex[T]) {
+	if len(s.filterAdjacent(node.Key, s.notReadyStatus)) != 0 {
+		return
+	}
+	if !s.consume(node.Key) {
+		// another worker already queued this node
+		return
+	}
+	s.queue.push(node.Key)
+}
+
+// done accounts for one more vertex having reached a terminal status, and
+// closes the queue once every vertex has.
+func (s *visitState[T]) done() {
+	s.mu.Lock()
+	s.remaining--
+	empty := s.remaining == 0
+	s.mu.Unlock()
+	if empty {
+		s.queue.close()
+	}
+}
+
+// hasUpstreamFailure reports whether any of node's upstream neighbours
+// ended up Failed or Skipped.
+func (s *visitState[T]) hasUpstreamFailure(node *Vertex[T]) bool {
+	return len(s.filterAdjacent(node.Key, s.options.Failed)) != 0 ||
+		len(s.filterAdjacent(node.Key, s.options.Skipped)) != 0
+}
+
+func (s *visitState[T]) consume(key T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = map[T]struct{}{}
+	}
+	if _, ok := s.seen[key]; ok {
+		return false
+	}
+	s.seen[key] = struct{}{}
+	return true
+}
+
+func (s *visitState[T]) recordOutcome(key T, status Status, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case status == s.options.Failed:
+		if s.failed == nil {
+			s.failed = map[T]error{}
+		}
+		s.failed[key] = err
+	case status == s.options.Skipped:
+		if s.skipped == nil {
+			s.skipped = map[T]struct{}{}
+		}
+		s.skipped[key] = struct{}{}
+	}
+}
+
+// outcomeError aggregates every recorded failure and skip into a single
+// error, or nil if the walk completed without any.
+func (s *visitState[T]) outcomeError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failed) == 0 && len(s.skipped) == 0 {
+		return nil
+	}
+
+	var failedKeys []string
+	keyByString := map[string]T{}
+	for key := range s.failed {
+		str := fmt.Sprint(key)
+		failedKeys = append(failedKeys, str)
+		keyByString[str] = key
+	}
+	sort.Strings(failedKeys)
+
+	var errs []error
+	for _, str := range failedKeys {
+		key := keyByString[str]
+		errs = append(errs, fmt.Errorf("%v failed: %w", key, s.failed[key]))
+	}
+
+	var skippedKeys []string
+	for key := range s.skipped {
+		skippedKeys = append(skippedKeys, fmt.Sprint(key))
+	}
+	sort.Strings(skippedKeys)
+	for _, str := range skippedKeys {
+		errs = append(errs, fmt.Errorf("%s skipped: a dependency failed", str))
+	}
+
+	return errors.Join(errs...)
+}
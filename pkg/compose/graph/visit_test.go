@@ -0,0 +1,180 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testStopped Status = iota
+	testStarted
+	testFailed
+	testSkipped
+)
+
+func testOptions() VisitOptions[string] {
+	return VisitOptions[string]{
+		NotVisited: testStopped,
+		Visited:    testStarted,
+		Failed:     testFailed,
+		Skipped:    testSkipped,
+	}
+}
+
+func TestVisitAbortOnFailureStopsDependents(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"}, // a depends on b
+		"b": nil,
+	})
+
+	var mu sync.Mutex
+	called := map[string]bool{}
+	fn := func(_ context.Context, key string) error {
+		mu.Lock()
+		called[key] = true
+		mu.Unlock()
+		if key == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	opts := testOptions()
+	opts.FailurePolicy = AbortOnFailure
+	err := Visit[string](context.Background(), g, fn, opts)
+	if err == nil {
+		t.Fatal("Visit should return b's error under AbortOnFailure")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if called["a"] {
+		t.Error("a should never be visited once its dependency b fails under AbortOnFailure")
+	}
+}
+
+func TestVisitSkipDependentsMarksDownstreamSkipped(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"}, // a depends on b, which fails
+		"b": nil,
+		"x": {"y"}, // x depends on y, an unrelated branch that should still run
+		"y": nil,
+	})
+
+	fn := func(_ context.Context, key string) error {
+		if key == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	opts := testOptions()
+	opts.FailurePolicy = SkipDependents
+	if err := Visit[string](context.Background(), g, fn, opts); err == nil {
+		t.Fatal("Visit should report b's failure and a's skip")
+	}
+
+	assertStatus := func(key string, want Status) {
+		v, ok := g.Vertex(key)
+		if !ok {
+			t.Fatalf("missing vertex %s", key)
+		}
+		if v.Status != want {
+			t.Errorf("vertex %s has status %v, want %v", key, v.Status, want)
+		}
+	}
+	assertStatus("b", testFailed)
+	assertStatus("a", testSkipped)
+	assertStatus("x", testStarted)
+	assertStatus("y", testStarted)
+}
+
+func TestVisitContinueOnFailureRunsDependentsAnyway(t *testing.T) {
+	g := newTestGraph(t, map[string][]string{
+		"a": {"b"},
+		"b": nil,
+	})
+
+	var mu sync.Mutex
+	called := map[string]bool{}
+	fn := func(_ context.Context, key string) error {
+		mu.Lock()
+		called[key] = true
+		mu.Unlock()
+		if key == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	opts := testOptions()
+	opts.FailurePolicy = ContinueOnFailure
+	if err := Visit[string](context.Background(), g, fn, opts); err == nil {
+		t.Fatal("Visit should still report b's failure under ContinueOnFailure")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !called["a"] {
+		t.Error("a should still be visited despite b's failure under ContinueOnFailure")
+	}
+}
+
+// TestVisitSkippedVertexDoesNotWaitOnLimiter is a regression test: a vertex
+// that ends up merely Skipped (its dependency failed) must never block on
+// the rate limiter, since it never calls the visitor function at all. Before
+// this was fixed, every skip paid a full limiter.Wait, turning an instant
+// skip-cascade into a slow drain.
+func TestVisitSkippedVertexDoesNotWaitOnLimiter(t *testing.T) {
+	deps := map[string][]string{"b": nil}
+	const dependents = 4
+	for i := 0; i < dependents; i++ {
+		deps[string(rune('d'+i))] = []string{"b"}
+	}
+	g := newTestGraph(t, deps)
+
+	fn := func(_ context.Context, key string) error {
+		if key == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	opts := testOptions()
+	opts.FailurePolicy = SkipDependents
+	opts.StartRate = 2 // one token every 500ms
+	opts.Burst = 1
+
+	start := time.Now()
+	if err := Visit[string](context.Background(), g, fn, opts); err == nil {
+		t.Fatal("Visit should report b's failure")
+	}
+	elapsed := time.Since(start)
+
+	// Only b's single fn call is ever rate-limited; the dependents are all
+	// skipped without touching the limiter. If a regression reintroduces the
+	// bug, each of the 4 dependents would additionally wait ~500ms for its
+	// own token, pushing this well past a second.
+	if elapsed > time.Second {
+		t.Errorf("Visit took %s, want well under 1s: skipped vertices appear to be waiting on the rate limiter", elapsed)
+	}
+}
@@ -91,33 +91,46 @@ func (s *composeService) start(ctx context.Context, projectName string, options
 		return err
 	}
 
-	err = InDependencyOrder(ctx, project, func(c context.Context, name string) error {
-		service, err := project.GetService(name)
-		if err != nil {
-			return err
-		}
+	traversalOptions := startTraversalOptions(options, project)
 
-		return s.startService(ctx, project, service, containers)
-	})
-	if err != nil {
+	executor := NewDockerExecutor(s, project, containers)
+	if err := StartInDependencyOrder(ctx, project, executor, options.Services, traversalOptions...); err != nil {
 		return err
 	}
 
 	if options.Wait {
-		depends := types.DependsOnConfig{}
-		for _, s := range project.Services {
-			depends[s.Name] = types.ServiceDependency{
-				Condition: getDependencyCondition(s, project),
+		services := project.Services
+		if len(options.Services) > 0 {
+			names, err := ServicesInDependencyOrderFor(project, options.Services)
+			if err != nil {
+				return err
+			}
+			services = types.Services{}
+			for _, svc := range project.Services {
+				if utils.Contains(names, svc.Name) {
+					services = append(services, svc)
+				}
 			}
 		}
+
 		if options.WaitTimeout > 0 {
 			withTimeout, cancel := context.WithTimeout(ctx, options.WaitTimeout)
 			ctx = withTimeout
 			defer cancel()
 		}
 
-		err = s.waitDependencies(ctx, project, depends, containers)
-		if err != nil {
+		// Wait through executor rather than batching on s.waitDependencies
+		// directly, so a non-Docker Executor can satisfy --wait the same way
+		// it satisfies the rest of compose up.
+		waitEg, waitCtx := errgroup.WithContext(ctx)
+		for _, svc := range services {
+			svc := svc
+			condition := getDependencyCondition(svc, project)
+			waitEg.Go(func() error {
+				return executor.Wait(waitCtx, svc.Name, condition)
+			})
+		}
+		if err := waitEg.Wait(); err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
 				return fmt.Errorf("application not healthy after %s", options.WaitTimeout)
 			}
@@ -128,6 +141,55 @@ func (s *composeService) start(ctx context.Context, projectName string, options
 	return eg.Wait()
 }
 
+// failurePolicyFromAPI translates the api package's wire-level
+// DependencyFailureAction into the FailurePolicy the graph traversal
+// understands. pkg/api can't import pkg/compose (pkg/compose already
+// imports pkg/api), hence the separate enum and this translation.
+func failurePolicyFromAPI(action api.DependencyFailureAction) FailurePolicy {
+	switch action {
+	case api.DependencyFailureSkipDependents:
+		return SkipDependents
+	case api.DependencyFailureContinue:
+		return ContinueAll
+	default:
+		return Abort
+	}
+}
+
+// startTraversalOptions builds the TraversalOptions StartInDependencyOrder
+// uses for a `compose up`/`compose start`, translating options.OnDependencyFailure
+// and wiring options.MaxConcurrency/StartRate/StartBurst into the scheduler
+// only when the caller actually set them, so a zero-valued StartOptions keeps
+// today's unbounded, unlimited-rate behavior.
+func startTraversalOptions(options api.StartOptions, project *types.Project) []func(*TraversalOptions) {
+	traversalOptions := []func(*TraversalOptions){
+		WithFailurePolicy(failurePolicyFromAPI(options.OnDependencyFailure)),
+		WithPriorityFn(serviceFanOutPriority(project)),
+	}
+	if options.MaxConcurrency > 0 {
+		traversalOptions = append(traversalOptions, WithMaxConcurrency(options.MaxConcurrency))
+	}
+	if options.StartRate > 0 {
+		traversalOptions = append(traversalOptions, WithStartRate(options.StartRate), WithBurst(options.StartBurst))
+	}
+	return traversalOptions
+}
+
+// serviceFanOutPriority ranks services by how many others directly depend on
+// them, so when several are ready to start at once, the ones that unblock
+// the most downstream work are dispatched first.
+func serviceFanOutPriority(project *types.Project) func(service string) int {
+	fanOut := map[string]int{}
+	for _, svc := range project.Services {
+		for dep := range svc.DependsOn {
+			fanOut[dep]++
+		}
+	}
+	return func(service string) int {
+		return fanOut[service]
+	}
+}
+
 // getDependencyCondition checks if service is depended on by other services
 // with service_completed_successfully condition, and applies that condition
 // instead, or --wait will never finish waiting for one-shot containers
@@ -0,0 +1,84 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+func TestStartTraversalOptionsLeavesSchedulerUnboundedByDefault(t *testing.T) {
+	project := &types.Project{}
+
+	opts := TraversalOptions{}
+	for _, option := range startTraversalOptions(api.StartOptions{}, project) {
+		option(&opts)
+	}
+
+	if opts.MaxConcurrency != 0 {
+		t.Errorf("MaxConcurrency = %d, want 0 (unbounded) when StartOptions doesn't set it", opts.MaxConcurrency)
+	}
+	if opts.StartRate != 0 {
+		t.Errorf("StartRate = %v, want 0 (unlimited) when StartOptions doesn't set it", opts.StartRate)
+	}
+	if opts.FailurePolicy != Abort {
+		t.Errorf("FailurePolicy = %v, want Abort by default", opts.FailurePolicy)
+	}
+}
+
+func TestStartTraversalOptionsWiresSchedulerKnobsFromAPI(t *testing.T) {
+	project := &types.Project{}
+
+	opts := TraversalOptions{}
+	for _, option := range startTraversalOptions(api.StartOptions{
+		MaxConcurrency:      4,
+		StartRate:           2,
+		StartBurst:          3,
+		OnDependencyFailure: api.DependencyFailureSkipDependents,
+	}, project) {
+		option(&opts)
+	}
+
+	if opts.MaxConcurrency != 4 {
+		t.Errorf("MaxConcurrency = %d, want 4 (from api.StartOptions.MaxConcurrency)", opts.MaxConcurrency)
+	}
+	if opts.StartRate != 2 {
+		t.Errorf("StartRate = %v, want 2 (from api.StartOptions.StartRate)", opts.StartRate)
+	}
+	if opts.Burst != 3 {
+		t.Errorf("Burst = %d, want 3 (from api.StartOptions.StartBurst)", opts.Burst)
+	}
+	if opts.FailurePolicy != SkipDependents {
+		t.Errorf("FailurePolicy = %v, want SkipDependents (from api.StartOptions.OnDependencyFailure)", opts.FailurePolicy)
+	}
+}
+
+func TestFailurePolicyFromAPI(t *testing.T) {
+	cases := map[api.DependencyFailureAction]FailurePolicy{
+		api.DependencyFailureAbort:          Abort,
+		api.DependencyFailureSkipDependents: SkipDependents,
+		api.DependencyFailureContinue:       ContinueAll,
+	}
+	for action, want := range cases {
+		if got := failurePolicyFromAPI(action); got != want {
+			t.Errorf("failurePolicyFromAPI(%v) = %v, want %v", action, got, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// GenerateSystemdOptions configures GenerateSystemd.
+type GenerateSystemdOptions struct {
+	// OutputDir, when set, writes one unit file per service plus the
+	// umbrella target unit into this directory. When empty, the generated
+	// units are only returned to the caller.
+	OutputDir string
+	// New makes the generated units recreate containers on every start
+	// instead of reusing whatever is already there.
+	New bool
+}
+
+// SystemdUnit is a single generated unit: its file name and contents.
+type SystemdUnit struct {
+	Name     string
+	Contents string
+}
+
+// GenerateSystemd walks the project's dependency graph and emits one
+// systemd `.service` unit per Compose service plus a `.target` unit that
+// depends on all of them, so systemd can replay the same startup order
+// `compose up` would use.
+func (s *composeService) GenerateSystemd(ctx context.Context, project *types.Project, options GenerateSystemdOptions) ([]SystemdUnit, error) {
+	graph, err := NewGraph(project.Services, ServiceStopped)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]SystemdUnit, 0, len(project.Services)+1)
+	names := make([]string, 0, len(project.Services))
+	for _, service := range project.Services {
+		vertex, ok := graph.Vertex(service.Name)
+		if !ok {
+			continue
+		}
+		units = append(units, generateServiceUnit(project.Name, service, vertex, options))
+		names = append(names, unitName(project.Name, service.Name))
+	}
+	sort.Strings(names)
+	units = append(units, generateTargetUnit(project.Name, names))
+
+	if options.OutputDir != "" {
+		if err := writeSystemdUnits(options.OutputDir, units); err != nil {
+			return nil, err
+		}
+	}
+	return units, nil
+}
+
+func generateServiceUnit(projectName string, service types.ServiceConfig, vertex *Vertex, options GenerateSystemdOptions) SystemdUnit {
+	var after, requires, wants []string
+	for _, dep := range vertex.GetChildren() {
+		unit := unitName(projectName, dep.Key)
+		after = append(after, unit)
+		condition := service.DependsOn[dep.Key].Condition
+		if condition == types.ServiceConditionCompletedSuccessfully || condition == types.ServiceConditionHealthy {
+			requires = append(requires, unit)
+		} else {
+			wants = append(wants, unit)
+		}
+	}
+	sort.Strings(after)
+	sort.Strings(requires)
+	sort.Strings(wants)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=Compose service %s (project %s)\n", service.Name, projectName)
+	if len(after) > 0 {
+		fmt.Fprintf(&b, "After=%s\n", strings.Join(after, " "))
+	}
+	if len(requires) > 0 {
+		fmt.Fprintf(&b, "Requires=%s\n", strings.Join(requires, " "))
+	}
+	if len(wants) > 0 {
+		fmt.Fprintf(&b, "Wants=%s\n", strings.Join(wants, " "))
+	}
+
+	fmt.Fprintf(&b, "\n[Service]\n")
+	// "docker compose up -d" forks the container and exits on its own; there
+	// is no long-running process for systemd to track, so this is a oneshot
+	// unit (not forking, which would need a PIDFile written before ExecStart
+	// returns) that stays "active" via RemainAfterExit.
+	fmt.Fprintf(&b, "Type=oneshot\n")
+	fmt.Fprintf(&b, "RemainAfterExit=yes\n")
+	fmt.Fprintf(&b, "Restart=%s\n", systemdRestartPolicy(service.Restart))
+	if options.New {
+		fmt.Fprintf(&b, "ExecStartPre=-/usr/bin/docker compose -p %s rm -f %s\n", projectName, service.Name)
+	}
+	fmt.Fprintf(&b, "ExecStart=/usr/bin/docker compose -p %s up -d %s\n", projectName, service.Name)
+	fmt.Fprintf(&b, "ExecStop=/usr/bin/docker compose -p %s stop %s\n", projectName, service.Name)
+
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=%s\n", targetUnitName(projectName))
+
+	return SystemdUnit{
+		Name:     unitName(projectName, service.Name),
+		Contents: b.String(),
+	}
+}
+
+func generateTargetUnit(projectName string, serviceUnits []string) SystemdUnit {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=Compose project %s\n", projectName)
+	if len(serviceUnits) > 0 {
+		fmt.Fprintf(&b, "Requires=%s\n", strings.Join(serviceUnits, " "))
+	}
+
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=multi-user.target\n")
+
+	return SystemdUnit{
+		Name:     targetUnitName(projectName),
+		Contents: b.String(),
+	}
+}
+
+func writeSystemdUnits(dir string, units []SystemdUnit) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating systemd unit directory: %w", err)
+	}
+	for _, unit := range units {
+		path := filepath.Join(dir, unit.Name)
+		if err := os.WriteFile(path, []byte(unit.Contents), 0o644); err != nil {
+			return fmt.Errorf("writing systemd unit %s: %w", unit.Name, err)
+		}
+	}
+	return nil
+}
+
+func systemdRestartPolicy(restart string) string {
+	switch restart {
+	case "always":
+		return "always"
+	case "on-failure":
+		return "on-failure"
+	case "unless-stopped":
+		return "always"
+	default:
+		return "no"
+	}
+}
+
+func unitBaseName(projectName, serviceName string) string {
+	return projectName + "-" + serviceName
+}
+
+func unitName(projectName, serviceName string) string {
+	return unitBaseName(projectName, serviceName) + ".service"
+}
+
+func targetUnitName(projectName string) string {
+	return projectName + ".target"
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2020 Docker Compose CLI authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+func TestGenerateServiceUnitIsOneshotNotForking(t *testing.T) {
+	g, err := NewGraph(types.Services{{Name: "web"}}, ServiceStopped)
+	if err != nil {
+		t.Fatalf("NewGraph: %v", err)
+	}
+	vertex, _ := g.Vertex("web")
+
+	unit := generateServiceUnit("myproject", types.ServiceConfig{Name: "web"}, vertex, GenerateSystemdOptions{})
+
+	if strings.Contains(unit.Contents, "Type=forking") {
+		t.Error("generated unit should not use Type=forking: ExecStart doesn't leave a tracked child process behind")
+	}
+	if !strings.Contains(unit.Contents, "Type=oneshot") {
+		t.Error("generated unit should use Type=oneshot")
+	}
+	if !strings.Contains(unit.Contents, "RemainAfterExit=yes") {
+		t.Error("generated unit should set RemainAfterExit=yes so it stays active after ExecStart returns")
+	}
+	if strings.Contains(unit.Contents, "PIDFile=") {
+		t.Error("generated unit should not declare a PIDFile: nothing writes one before ExecStart returns")
+	}
+}
+
+func TestGenerateServiceUnitUsesGraphEdgesForOrdering(t *testing.T) {
+	services := types.Services{
+		{Name: "web", DependsOn: types.DependsOnConfig{
+			"db": types.ServiceDependency{Condition: types.ServiceConditionHealthy},
+		}},
+		{Name: "db"},
+		{Name: "unrelated"},
+	}
+	g, err := NewGraph(services, ServiceStopped)
+	if err != nil {
+		t.Fatalf("NewGraph: %v", err)
+	}
+	vertex, _ := g.Vertex("web")
+
+	unit := generateServiceUnit("myproject", services[0], vertex, GenerateSystemdOptions{})
+
+	if !strings.Contains(unit.Contents, "After=myproject-db.service") {
+		t.Errorf("expected unit to order After= its graph dependency db, got:\n%s", unit.Contents)
+	}
+	if !strings.Contains(unit.Contents, "Requires=myproject-db.service") {
+		t.Errorf("a healthy-condition dependency should become Requires=, got:\n%s", unit.Contents)
+	}
+	if strings.Contains(unit.Contents, "unrelated") {
+		t.Errorf("unit should not reference a service it doesn't depend on, got:\n%s", unit.Contents)
+	}
+}
+
+func TestGenerateTargetUnitRequiresEveryServiceUnit(t *testing.T) {
+	unit := generateTargetUnit("myproject", []string{"myproject-db.service", "myproject-web.service"})
+	if !strings.Contains(unit.Contents, "Requires=myproject-db.service myproject-web.service") {
+		t.Errorf("target unit should require every service unit, got:\n%s", unit.Contents)
+	}
+}